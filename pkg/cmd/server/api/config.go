@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/v1/yaml"
+)
+
+// ReadMasterConfig reads and decodes a MasterConfig from filename. The file is always
+// parsed as YAML, which is a superset of JSON, so a well-formed JSON file works too;
+// the filename's extension is not consulted.
+func ReadMasterConfig(filename string) (*MasterConfig, error) {
+	config := &MasterConfig{}
+	if err := readConfig(filename, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ReadNodeConfig reads and decodes a NodeConfig from filename. As with
+// ReadMasterConfig, the file is always parsed as YAML regardless of its extension.
+func ReadNodeConfig(filename string) (*NodeConfig, error) {
+	config := &NodeConfig{}
+	if err := readConfig(filename, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// WriteMasterConfig serializes the given MasterConfig to filename as YAML, creating
+// or truncating the file as needed.
+func WriteMasterConfig(filename string, config *MasterConfig) error {
+	return writeConfig(filename, config)
+}
+
+// WriteNodeConfig serializes the given NodeConfig to filename as YAML, creating or
+// truncating the file as needed.
+func WriteNodeConfig(filename string, config *NodeConfig) error {
+	return writeConfig(filename, config)
+}
+
+func readConfig(filename string, out interface{}) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("unable to read config file %q: %v", filename, err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unable to parse config file %q: %v", filename, err)
+	}
+	return nil
+}
+
+func writeConfig(filename string, in interface{}) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("unable to serialize config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("unable to create config directory for %q: %v", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("unable to write config file %q: %v", filename, err)
+	}
+	return nil
+}
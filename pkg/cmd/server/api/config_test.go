@@ -0,0 +1,99 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	kapi "github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestMasterConfigRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "master-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := &MasterConfig{
+		TypeMeta:   kapi.TypeMeta{Kind: "MasterConfig"},
+		BindAddr:   "0.0.0.0:8443",
+		MasterAddr: "https://localhost:8443",
+		AssetAddr:  "localhost:8444",
+
+		EtcdClientInfo: EtcdConnectionInfo{URLs: []string{"http://localhost:4001"}},
+		EtcdStorageConfig: EtcdStorageConfig{
+			OpenShiftStorageVersion:  "v1beta1",
+			OpenShiftStoragePrefix:   "/openshift.io",
+			KubernetesStorageVersion: "v1beta1",
+			KubernetesStoragePrefix:  "/registry",
+		},
+		SchedulerConfigFile: "/etc/openshift/scheduler.json",
+		SessionSecrets:      []string{"abcdef0123456789"},
+	}
+
+	filename := filepath.Join(dir, "master-config.yaml")
+	if err := WriteMasterConfig(filename, in); err != nil {
+		t.Fatalf("WriteMasterConfig() error = %v", err)
+	}
+
+	out, err := ReadMasterConfig(filename)
+	if err != nil {
+		t.Fatalf("ReadMasterConfig() error = %v", err)
+	}
+
+	if out.Kind != "MasterConfig" {
+		t.Errorf("Kind = %q, want %q", out.Kind, "MasterConfig")
+	}
+	if out.BindAddr != in.BindAddr {
+		t.Errorf("BindAddr = %q, want %q", out.BindAddr, in.BindAddr)
+	}
+	if out.SchedulerConfigFile != in.SchedulerConfigFile {
+		t.Errorf("SchedulerConfigFile = %q, want %q", out.SchedulerConfigFile, in.SchedulerConfigFile)
+	}
+	if out.EtcdStorageConfig != in.EtcdStorageConfig {
+		t.Errorf("EtcdStorageConfig = %+v, want %+v", out.EtcdStorageConfig, in.EtcdStorageConfig)
+	}
+	if !reflect.DeepEqual(out.SessionSecrets, in.SessionSecrets) {
+		t.Errorf("SessionSecrets = %v, want %v", out.SessionSecrets, in.SessionSecrets)
+	}
+}
+
+func TestNodeConfigRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-config")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := &NodeConfig{
+		TypeMeta:          kapi.TypeMeta{Kind: "NodeConfig"},
+		NodeHost:          "node1.example.com",
+		BindHost:          "0.0.0.0",
+		MasterAddr:        "https://master.example.com:8443",
+		VolumeDirectory:   "openshift.local.volumes",
+		NetworkPluginName: "redhat/openshift-ovs-multitenant",
+	}
+
+	filename := filepath.Join(dir, "node-config.yaml")
+	if err := WriteNodeConfig(filename, in); err != nil {
+		t.Fatalf("WriteNodeConfig() error = %v", err)
+	}
+
+	out, err := ReadNodeConfig(filename)
+	if err != nil {
+		t.Fatalf("ReadNodeConfig() error = %v", err)
+	}
+
+	if out.Kind != "NodeConfig" {
+		t.Errorf("Kind = %q, want %q", out.Kind, "NodeConfig")
+	}
+	if out.NodeHost != in.NodeHost {
+		t.Errorf("NodeHost = %q, want %q", out.NodeHost, in.NodeHost)
+	}
+	if out.NetworkPluginName != in.NetworkPluginName {
+		t.Errorf("NetworkPluginName = %q, want %q", out.NetworkPluginName, in.NetworkPluginName)
+	}
+}
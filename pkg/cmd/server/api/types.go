@@ -0,0 +1,104 @@
+package api
+
+import (
+	kapi "github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// MasterConfig is the fully specified configuration for a master node, as loaded from
+// a --config file or written out via --write-config. It intentionally mirrors the
+// flags accepted by NewCommandStartServer so operators can migrate between the two.
+type MasterConfig struct {
+	kapi.TypeMeta
+
+	// BindAddr is the address to listen for connections on (host, host:port, or URL).
+	BindAddr string
+	// MasterAddr is the address the master can be reached on (host, host:port, or URL).
+	MasterAddr string
+	// AssetAddr is the address the asset (web console) server listens on.
+	AssetAddr string
+
+	// EtcdClientInfo describes how to contact the etcd cluster backing this master.
+	EtcdClientInfo EtcdConnectionInfo
+	// EtcdStorageConfig carries the per-API-group storage version and key prefix.
+	EtcdStorageConfig EtcdStorageConfig
+
+	CertDir string
+
+	CORSAllowedOrigins    []string
+	RequireAuthentication bool
+
+	// SessionSecrets authenticate and encrypt the web console's session cookie. If
+	// empty, a random secret is generated at startup instead.
+	SessionSecrets []string
+
+	PortalNet string
+
+	MasterServiceNamespace string
+
+	// SchedulerConfigFile points at a scheduler policy file, if any.
+	SchedulerConfigFile string
+
+	// KubernetesMasterConfig configures the embedded Kubernetes master. It is nil when
+	// KubernetesAddr points at an external Kubernetes apiserver instead.
+	KubernetesMasterConfig *KubernetesMasterConfig
+
+	// KubernetesAddr is the address of an external Kubernetes apiserver to proxy to.
+	// It is ignored when KubernetesMasterConfig is set.
+	KubernetesAddr string
+}
+
+// KubernetesMasterConfig is the configuration for the Kubernetes master that is
+// embedded in (or proxied through) an OpenShift master.
+type KubernetesMasterConfig struct {
+	kapi.TypeMeta
+
+	MasterHost string
+	MasterPort int
+
+	NodeList []string
+
+	ServicesSubnet string
+
+	// NetworkPluginName is the SDN network plugin nodes in this cluster should load.
+	// Empty disables the subsystem.
+	NetworkPluginName string
+	// NetworkCIDR is the cluster-wide CIDR that per-node subnets are carved out of.
+	NetworkCIDR string
+	// HostSubnetLength is the size, in bits, of each node's subnet.
+	HostSubnetLength uint
+}
+
+// NodeConfig is the fully specified configuration for a node, as loaded from a
+// --config file or written out via --write-config.
+type NodeConfig struct {
+	kapi.TypeMeta
+
+	NodeHost   string
+	BindHost   string
+	MasterAddr string
+
+	VolumeDirectory string
+
+	// NetworkPluginName is the name of the network plugin to initialize before the
+	// kubelet starts, e.g. "redhat/openshift-ovs-multitenant" or "kubenet". Empty
+	// disables the subsystem entirely.
+	NetworkPluginName string
+
+	MasterServiceNamespace string
+}
+
+// EtcdConnectionInfo describes how to reach an etcd cluster.
+type EtcdConnectionInfo struct {
+	URLs []string
+}
+
+// EtcdStorageConfig carries independent storage versions and key prefixes for the
+// OpenShift and Kubernetes resources kept in the same etcd cluster, so that the two
+// API groups can be migrated to new storage versions independently.
+type EtcdStorageConfig struct {
+	OpenShiftStorageVersion string
+	OpenShiftStoragePrefix  string
+
+	KubernetesStorageVersion string
+	KubernetesStoragePrefix  string
+}
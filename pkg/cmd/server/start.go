@@ -1,12 +1,15 @@
 package server
 
 import (
+	"crypto/rand"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +20,7 @@ import (
 	kclient "github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
+	kubeletclient "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/client"
 	kmaster "github.com/GoogleCloudPlatform/kubernetes/pkg/master"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/plugin/pkg/admission/admit"
@@ -26,6 +30,7 @@ import (
 
 	"github.com/openshift/origin/pkg/api/latest"
 	"github.com/openshift/origin/pkg/cmd/flagtypes"
+	"github.com/openshift/origin/pkg/cmd/server/api"
 	"github.com/openshift/origin/pkg/cmd/server/crypto"
 	"github.com/openshift/origin/pkg/cmd/server/etcd"
 	"github.com/openshift/origin/pkg/cmd/server/kubernetes"
@@ -76,6 +81,13 @@ type config struct {
 	KubernetesAddr flagtypes.Addr
 	PortalNet      flagtypes.IPNet
 
+	// AssetAddr is the address the web console server listens on. It is never
+	// flag-backed: left empty, it defaults to the master address's host on the port
+	// above BindAddr's; a --config file may override it, and the value actually used
+	// is what --write-config persists, so an administrator's edit round-trips instead
+	// of being silently recomputed.
+	AssetAddr string
+
 	Hostname  string
 	VolumeDir string
 
@@ -83,14 +95,58 @@ type config struct {
 
 	CertDir string
 
-	StorageVersion string
+	StorageVersion     string
+	StoragePrefix      string
+	KubeStorageVersion string
+	KubeStoragePrefix  string
 
 	NodeList flagtypes.StringList
 
 	CORSAllowedOrigins    flagtypes.StringList
 	RequireAuthentication bool
 
+	// SessionSecrets authenticate and encrypt the browser session cookie used for
+	// web console login. They are never flag-backed (a secret on the command line
+	// would leak into process listings and shell history): a config file may supply
+	// them, otherwise a random secret is generated at startup.
+	SessionSecrets []string
+
 	MasterServiceNamespace string
+
+	// NetworkPluginName is the name of the SDN network plugin the node should load
+	// before the kubelet starts (e.g. "redhat/openshift-ovs-multitenant"). Empty
+	// disables the subsystem and falls back to the existing Docker bridge networking.
+	NetworkPluginName string
+	// NetworkCIDR is the cluster-wide CIDR the master carves per-node subnets out of
+	// when a network plugin is in use.
+	NetworkCIDR string
+	// HostSubnetLength is the size, in bits, of the per-node subnet carved out of
+	// NetworkCIDR.
+	HostSubnetLength uint
+
+	// SchedulerConfigFile points at a scheduler policy file, if any.
+	SchedulerConfigFile string
+
+	// ConfigFile, if set, loads a versioned MasterConfig/NodeConfig from disk and uses
+	// it in place of the flags above.
+	ConfigFile string
+	// WriteConfigTo, if set, writes the resolved configuration (as it would be loaded
+	// by ConfigFile) to the given path instead of starting the server.
+	WriteConfigTo string
+
+	// MigrateEtcdPrefixFrom and MigrateEtcdPrefixTo, if both set, cause the command to
+	// copy every key under the former prefix to the latter and exit, instead of
+	// starting the server.
+	MigrateEtcdPrefixFrom string
+	MigrateEtcdPrefixTo   string
+
+	// KubeletPort and KubeletCertificateAuthority configure how the master's
+	// controllers reach the kubelet on each node (for node status, logs, exec, and
+	// port-forward).
+	KubeletPort                 int
+	KubeletCertificateAuthority string
+	KubeletClientQPS            float32
+	KubeletClientBurst          int
 }
 
 // NewCommandStartServer provides a CLI handler for 'start' command
@@ -113,6 +169,15 @@ func NewCommandStartServer(name string) *cobra.Command {
 		Hostname:               hostname,
 		NodeList:               flagtypes.StringList{"127.0.0.1"},
 		MasterServiceNamespace: kapi.NamespaceDefault,
+
+		StorageVersion:     latest.Version,
+		StoragePrefix:      "/openshift.io",
+		KubeStorageVersion: klatest.Version,
+		KubeStoragePrefix:  "/registry",
+
+		KubeletPort:        10250,
+		KubeletClientQPS:   10.0,
+		KubeletClientBurst: 20,
 	}
 
 	cmd := &cobra.Command{
@@ -143,6 +208,26 @@ func NewCommandStartServer(name string) *cobra.Command {
 	flag.Var(&cfg.CORSAllowedOrigins, "cors-allowed-origins", "List of allowed origins for CORS, comma separated.  An allowed origin can be a regular expression to support subdomain matching.  CORS is enabled for localhost, 127.0.0.1, and the asset server by default.")
 	flag.BoolVar(&cfg.RequireAuthentication, "require-authentication", false, "Require authentication token for API access.")
 	flag.StringVar(&cfg.MasterServiceNamespace, "master_service_namespace", "The namespace from which the kubernetes master services should be injected into pods")
+	flag.StringVar(&cfg.SchedulerConfigFile, "scheduler-config", "", "Location of the scheduler policy file, if any. Leave empty to use the default scheduler.")
+
+	flag.StringVar(&cfg.NetworkPluginName, "network-plugin", "", "The name of the network plugin to use for pod networking on the node (e.g. openshift-sdn multitenant, flannel). Leave empty to use the default Docker bridge networking.")
+	flag.StringVar(&cfg.NetworkCIDR, "network-cidr", "10.1.0.0/16", "The CIDR string representing the network that node subnets are allocated from when a network plugin is in use.")
+	flag.UintVar(&cfg.HostSubnetLength, "host-subnet-length", 8, "The number of bits of network-cidr to allocate to each node's subnet when a network plugin is in use.")
+
+	flag.StringVar(&cfg.StorageVersion, "storage-version", cfg.StorageVersion, "The API version to store OpenShift resources as in etcd.")
+	flag.StringVar(&cfg.StoragePrefix, "storage-prefix", cfg.StoragePrefix, "The etcd key prefix OpenShift resources are stored under.")
+	flag.StringVar(&cfg.KubeStorageVersion, "kubernetes-storage-version", cfg.KubeStorageVersion, "The API version to store Kubernetes resources as in etcd.")
+	flag.StringVar(&cfg.KubeStoragePrefix, "kubernetes-storage-prefix", cfg.KubeStoragePrefix, "The etcd key prefix Kubernetes resources are stored under.")
+	flag.IntVar(&cfg.KubeletPort, "kubelet-port", cfg.KubeletPort, "The port to reach kubelets on for node status, logs, exec, and port-forward.")
+	flag.StringVar(&cfg.KubeletCertificateAuthority, "kubelet-certificate-authority", "", "The path to a cert file for the certificate authority that signed the kubelet's serving certificate, used to validate the kubelet's identity over HTTPS.")
+	flag.Float32Var(&cfg.KubeletClientQPS, "kubelet-client-qps", cfg.KubeletClientQPS, "The maximum QPS controllers may make against the kubelet API.")
+	flag.IntVar(&cfg.KubeletClientBurst, "kubelet-client-burst", cfg.KubeletClientBurst, "The maximum burst for throttling against the kubelet API.")
+
+	flag.StringVar(&cfg.MigrateEtcdPrefixFrom, "migrate-etcd-prefix-from", "", "If set, copy every key under this etcd prefix to --migrate-etcd-prefix-to and exit, instead of starting the server. Use to bump one API version's storage prefix without invalidating the other.")
+	flag.StringVar(&cfg.MigrateEtcdPrefixTo, "migrate-etcd-prefix-to", "", "The destination etcd prefix for --migrate-etcd-prefix-from.")
+
+	flag.StringVar(&cfg.ConfigFile, "config", "", "Location of the master/node config file to run from. When set, overrides the flags above.")
+	flag.StringVar(&cfg.WriteConfigTo, "write-config", "", "If set, write the master-config.yaml and/or node-config.yaml that would be used to run the server into this directory and exit, instead of starting the server.")
 
 	cfg.Docker.InstallFlags(flag)
 
@@ -155,6 +240,12 @@ func start(cfg *config, args []string) error {
 		return errors.New("You may start an OpenShift all-in-one server with no arguments, or pass 'master' or 'node' to run in that role.")
 	}
 
+	if len(cfg.ConfigFile) > 0 {
+		if err := mergeConfigFile(cfg); err != nil {
+			return fmt.Errorf("Error loading config file %q: %v", cfg.ConfigFile, err)
+		}
+	}
+
 	var startEtcd, startNode, startMaster bool
 	if len(args) == 1 {
 		switch args[0] {
@@ -193,6 +284,28 @@ func start(cfg *config, args []string) error {
 		cfg.KubernetesAddr = cfg.MasterAddr
 	}
 
+	if len(cfg.SessionSecrets) == 0 {
+		secret, err := generateSessionSecret()
+		if err != nil {
+			return fmt.Errorf("Unable to generate a session secret: %v", err)
+		}
+		cfg.SessionSecrets = []string{secret}
+	}
+
+	if len(cfg.WriteConfigTo) > 0 {
+		return writeConfigFile(cfg, startMaster, startNode, startKube)
+	}
+
+	if len(cfg.MigrateEtcdPrefixFrom) > 0 && len(cfg.MigrateEtcdPrefixTo) > 0 {
+		etcdClient, err := getEtcdClient(cfg)
+		if err != nil {
+			return err
+		}
+		return migrateEtcdPrefix(etcdClient, cfg.MigrateEtcdPrefixFrom, cfg.MigrateEtcdPrefixTo)
+	}
+
+	supervisor := NewSupervisor()
+
 	if startMaster {
 		if len(cfg.NodeList) == 1 && cfg.NodeList[0] == "127.0.0.1" {
 			cfg.NodeList[0] = cfg.Hostname
@@ -208,7 +321,7 @@ func start(cfg *config, args []string) error {
 				MasterAddr:   cfg.EtcdAddr.URL.Host,
 				EtcdDir:      cfg.EtcdDir,
 			}
-			etcdConfig.Run()
+			supervisor.Register("etcd", AdaptSupervised(etcdConfig.Run))
 		}
 
 		// Connect and setup etcd interfaces
@@ -216,16 +329,23 @@ func start(cfg *config, args []string) error {
 		if err != nil {
 			return err
 		}
-		etcdHelper, err := origin.NewEtcdHelper(cfg.StorageVersion, etcdClient)
+		supervisor.OnShutdown(func() error {
+			etcdClient.Close()
+			return nil
+		})
+		etcdHelper, err := origin.NewEtcdHelper(cfg.StorageVersion, cfg.StoragePrefix, etcdClient)
 		if err != nil {
 			return fmt.Errorf("Error setting up server storage: %v", err)
 		}
-		ketcdHelper, err := kmaster.NewEtcdHelper(etcdClient, klatest.Version)
+		ketcdHelper, err := kmaster.NewEtcdHelper(etcdClient, cfg.KubeStorageVersion, cfg.KubeStoragePrefix)
 		if err != nil {
 			return fmt.Errorf("Error setting up Kubernetes server storage: %v", err)
 		}
 
-		assetAddr := net.JoinHostPort(cfg.MasterAddr.Host, strconv.Itoa(cfg.BindAddr.Port+1))
+		assetAddr := cfg.AssetAddr
+		if len(assetAddr) == 0 {
+			assetAddr = net.JoinHostPort(cfg.MasterAddr.Host, strconv.Itoa(cfg.BindAddr.Port+1))
+		}
 
 		// always include the all-in-one server's web console as an allowed CORS origin
 		// always include localhost as an allowed CORS origin
@@ -303,6 +423,20 @@ func start(cfg *config, args []string) error {
 				}
 			}
 
+			// Bootstrap a client cert controllers can use to reach the kubelet directly
+			kubeletClientCert, err := ca.MakeClientCert("kubelet-client")
+			if err != nil {
+				return err
+			}
+			osmaster.KubeletClientConfig = kubeletclient.KubeletConfig{
+				Port:        uint(cfg.KubeletPort),
+				EnableHttps: true,
+				CAFile:      cfg.KubeletCertificateAuthority,
+				CertFile:    kubeletClientCert.CertFile,
+				KeyFile:     kubeletClientCert.KeyFile,
+				HTTPTimeout: 5 * time.Second,
+			}
+
 			// Save cert roots
 			roots = x509.NewCertPool()
 			for _, root := range ca.Config.Roots {
@@ -313,21 +447,40 @@ func start(cfg *config, args []string) error {
 			osClientConfig := kclient.Config{Host: cfg.MasterAddr.URL.String(), Version: latest.Version}
 			osmaster.OSClientConfig = osClientConfig
 			osmaster.DeployerOSClientConfig = osClientConfig
+
+			osmaster.KubeletClientConfig = kubeletclient.KubeletConfig{
+				Port:        uint(cfg.KubeletPort),
+				EnableHttps: false,
+				HTTPTimeout: 5 * time.Second,
+			}
 		}
 
 		osmaster.BuildClients()
 		osmaster.EnsureCORSAllowedOrigins(cfg.CORSAllowedOrigins)
 
+		serviceAccountTokenGetter, err := newServiceAccountTokenGetter(cfg, osmaster, ketcdHelper, startKube)
+		if err != nil {
+			return fmt.Errorf("Unable to configure service account tokens: %v", err)
+		}
+		osmaster.ServiceAccountTokenGetter = serviceAccountTokenGetter
+		osmaster.EnsureDefaultNamespaceServiceAccounts()
+
 		auth := &origin.AuthConfig{
-			MasterAddr:     cfg.MasterAddr.URL.String(),
-			MasterRoots:    roots,
-			SessionSecrets: []string{"secret"},
-			EtcdHelper:     etcdHelper,
+			MasterAddr:                cfg.MasterAddr.URL.String(),
+			MasterRoots:               roots,
+			SessionSecrets:            cfg.SessionSecrets,
+			EtcdHelper:                etcdHelper,
+			ServiceAccountTokenGetter: serviceAccountTokenGetter,
 		}
 
 		if startKube {
 			portalNet := net.IPNet(cfg.PortalNet)
 
+			kubeletClient, err := kubeletclient.NewKubeletClient(&osmaster.KubeletClientConfig)
+			if err != nil {
+				return fmt.Errorf("Unable to configure Kubelet client: %v", err)
+			}
+
 			kmaster := &kubernetes.MasterConfig{
 				MasterHost:       cfg.MasterAddr.Host,
 				MasterPort:       cfg.MasterAddr.Port,
@@ -337,30 +490,47 @@ func start(cfg *config, args []string) error {
 				KubeClient:       osmaster.KubeClient(),
 				Authorizer:       apiserver.NewAlwaysAllowAuthorizer(),
 				AdmissionControl: admit.NewAlwaysAdmit(),
+
+				NetworkPluginName: cfg.NetworkPluginName,
+				NetworkCIDR:       cfg.NetworkCIDR,
+				HostSubnetLength:  cfg.HostSubnetLength,
+
+				SchedulerConfigFile: cfg.SchedulerConfigFile,
+
+				KubeletClient: kubeletClient,
 			}
 			kmaster.EnsurePortalFlags()
 
-			osmaster.RunAPI(kmaster, auth, osmaster, &origin.SwaggerAPI{})
+			supervisor.Register("apiserver", AdaptSupervised(func() { osmaster.RunAPI(kmaster, auth, osmaster, &origin.SwaggerAPI{}) }))
 
-			kmaster.RunScheduler()
-			kmaster.RunReplicationController()
-			kmaster.RunEndpointController()
-			kmaster.RunMinionController()
+			supervisor.Register("scheduler", AdaptSupervised(kmaster.RunScheduler))
+			supervisor.Register("replication-controller", AdaptSupervised(kmaster.RunReplicationController))
+			supervisor.Register("endpoint-controller", AdaptSupervised(kmaster.RunEndpointController))
+			supervisor.Register("minion-controller", AdaptSupervised(kmaster.RunMinionController))
+			supervisor.Register("node-controller", AdaptSupervised(func() { kmaster.RunNodeController(cfg.KubeletClientQPS, cfg.KubeletClientBurst) }))
+
+			if len(cfg.NetworkPluginName) > 0 {
+				supervisor.Register("sdn-controller", AdaptSupervised(kmaster.RunSDNController))
+			}
 
 		} else {
-			osmaster.RunAPI(auth, osmaster, &origin.SwaggerAPI{})
+			supervisor.Register("apiserver", AdaptSupervised(func() { osmaster.RunAPI(auth, osmaster, &origin.SwaggerAPI{}) }))
 		}
 
 		// TODO: recording should occur in individual components
 		record.StartRecording(osmaster.KubeClient().Events(""), kapi.EventSource{Component: "master"})
-
-		osmaster.RunAssetServer()
-		osmaster.RunBuildController()
-		osmaster.RunBuildImageChangeTriggerController()
-		osmaster.RunDeploymentController()
-		osmaster.RunDeploymentConfigController()
-		osmaster.RunDeploymentConfigChangeController()
-		osmaster.RunDeploymentImageChangeTriggerController()
+		supervisor.OnShutdown(func() error {
+			record.Eventf(&kapi.ObjectReference{Kind: "ComponentStatus", Name: "master"}, "ShuttingDown", "Master is shutting down")
+			return nil
+		})
+
+		supervisor.Register("asset-server", AdaptSupervised(osmaster.RunAssetServer))
+		supervisor.Register("build-controller", AdaptSupervised(osmaster.RunBuildController))
+		supervisor.Register("build-image-change-trigger-controller", AdaptSupervised(osmaster.RunBuildImageChangeTriggerController))
+		supervisor.Register("deployment-controller", AdaptSupervised(osmaster.RunDeploymentController))
+		supervisor.Register("deployment-config-controller", AdaptSupervised(osmaster.RunDeploymentConfigController))
+		supervisor.Register("deployment-config-change-controller", AdaptSupervised(osmaster.RunDeploymentConfigChangeController))
+		supervisor.Register("deployment-image-change-trigger-controller", AdaptSupervised(osmaster.RunDeploymentImageChangeTriggerController))
 	}
 
 	if startNode {
@@ -368,6 +538,12 @@ func start(cfg *config, args []string) error {
 		if err != nil {
 			return err
 		}
+		if !startMaster {
+			supervisor.OnShutdown(func() error {
+				etcdClient.Close()
+				return nil
+			})
+		}
 
 		if !startMaster {
 			// TODO: recording should occur in individual components
@@ -383,6 +559,7 @@ func start(cfg *config, args []string) error {
 			VolumeDir: cfg.VolumeDir,
 
 			NetworkContainerImage: env("KUBERNETES_NETWORK_CONTAINER_IMAGE", kubelet.NetworkContainerImage),
+			NetworkPluginName:     cfg.NetworkPluginName,
 
 			EtcdClient:             etcdClient,
 			MasterServiceNamespace: cfg.MasterServiceNamespace,
@@ -391,11 +568,18 @@ func start(cfg *config, args []string) error {
 		nodeConfig.EnsureVolumeDir()
 		nodeConfig.EnsureDocker(cfg.Docker)
 
-		nodeConfig.RunProxy()
-		nodeConfig.RunKubelet()
+		if len(cfg.NetworkPluginName) > 0 {
+			// Block kubelet updates until the overlay network is ready; RunSDNController
+			// wires its readiness signal into nodeConfig's kubelet StartUpdates channel.
+			// RunOnce still recovers a panic here instead of silently killing the node.
+			supervisor.RunOnce("node-sdn-controller", Adapt(nodeConfig.RunSDNController))
+		}
+
+		supervisor.Register("proxy", AdaptSupervised(nodeConfig.RunProxy))
+		supervisor.Register("kubelet", AdaptSupervised(nodeConfig.RunKubelet))
 	}
 
-	select {}
+	supervisor.Wait()
 
 	return nil
 }
@@ -421,6 +605,58 @@ func getEtcdClient(cfg *config) (*etcdclient.Client, error) {
 	return etcdClient, nil
 }
 
+// migrateEtcdPrefix copies every key under fromPrefix to the same relative path under
+// toPrefix, leaving the source keys untouched. It lets an operator bump the storage
+// prefix for one API group (e.g. after a --storage-version change) without disturbing
+// resources stored under the other group's prefix in the same etcd cluster.
+func migrateEtcdPrefix(etcdClient *etcdclient.Client, fromPrefix, toPrefix string) error {
+	resp, err := etcdClient.Get(fromPrefix, false, true)
+	if err != nil {
+		return fmt.Errorf("Unable to read keys under %q: %v", fromPrefix, err)
+	}
+	if resp.Node == nil {
+		return nil
+	}
+	return copyEtcdNode(resp.Node, fromPrefix, toPrefix, func(key, value string) error {
+		_, err := etcdClient.Set(key, value, 0)
+		return err
+	})
+}
+
+// copyEtcdNode walks node and, for every leaf underneath it, calls set with its key
+// rewritten from fromPrefix to toPrefix and its value unchanged. It is split out of
+// migrateEtcdPrefix so the tree walk can be unit tested without a live etcd client.
+func copyEtcdNode(node *etcdclient.Node, fromPrefix, toPrefix string, set func(key, value string) error) error {
+	if !strings.HasPrefix(node.Key, fromPrefix) {
+		return fmt.Errorf("Key %q is not under %q", node.Key, fromPrefix)
+	}
+	destKey := toPrefix + strings.TrimPrefix(node.Key, fromPrefix)
+	if node.Dir {
+		for _, child := range node.Nodes {
+			if err := copyEtcdNode(child, fromPrefix, toPrefix, set); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := set(destKey, node.Value); err != nil {
+		return fmt.Errorf("Unable to copy %q to %q: %v", node.Key, destKey, err)
+	}
+	glog.V(2).Infof("Copied %s -> %s", node.Key, destKey)
+	return nil
+}
+
+// generateSessionSecret returns a random, URL-safe secret suitable for authenticating
+// and encrypting the web console's session cookie, so that clusters don't all default
+// to the same hardcoded value.
+func generateSessionSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Couldn't generate a random secret: %v", err)
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
 // defaultHostname returns the default hostname for this system.
 func defaultHostname() (string, error) {
 	// Note: We use exec here instead of os.Hostname() because we
@@ -478,6 +714,176 @@ func defaultMasterAddress(cfg *config) error {
 	return nil
 }
 
+// mergeConfigFile loads cfg.ConfigFile and overlays its values onto cfg, replacing the
+// flag-derived defaults. Which of MasterConfig or NodeConfig was written to the file is
+// decided by its Kind field; gopkg.in/v1/yaml silently ignores the fields it doesn't
+// recognize, so a master config fed to ReadNodeConfig (or vice versa) would otherwise
+// decode without error into a mostly-empty struct instead of failing loudly.
+func mergeConfigFile(cfg *config) error {
+	masterConfig, err := api.ReadMasterConfig(cfg.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if masterConfig.Kind == "MasterConfig" {
+		if err := cfg.BindAddr.Set(masterConfig.BindAddr); err != nil {
+			return err
+		}
+		if err := cfg.MasterAddr.Set(masterConfig.MasterAddr); err != nil {
+			return err
+		}
+		cfg.AssetAddr = masterConfig.AssetAddr
+		cfg.CertDir = masterConfig.CertDir
+		cfg.CORSAllowedOrigins = flagtypes.StringList(masterConfig.CORSAllowedOrigins)
+		cfg.RequireAuthentication = masterConfig.RequireAuthentication
+		cfg.SessionSecrets = masterConfig.SessionSecrets
+		cfg.StorageVersion = masterConfig.EtcdStorageConfig.OpenShiftStorageVersion
+		cfg.StoragePrefix = masterConfig.EtcdStorageConfig.OpenShiftStoragePrefix
+		cfg.KubeStorageVersion = masterConfig.EtcdStorageConfig.KubernetesStorageVersion
+		cfg.KubeStoragePrefix = masterConfig.EtcdStorageConfig.KubernetesStoragePrefix
+		cfg.MasterServiceNamespace = masterConfig.MasterServiceNamespace
+		cfg.SchedulerConfigFile = masterConfig.SchedulerConfigFile
+		if len(masterConfig.EtcdClientInfo.URLs) > 0 {
+			if err := cfg.EtcdAddr.Set(masterConfig.EtcdClientInfo.URLs[0]); err != nil {
+				return err
+			}
+		}
+		if kubeConfig := masterConfig.KubernetesMasterConfig; kubeConfig != nil {
+			cfg.NodeList = flagtypes.StringList(kubeConfig.NodeList)
+			if err := cfg.PortalNet.Set(kubeConfig.ServicesSubnet); err != nil {
+				return err
+			}
+			cfg.NetworkPluginName = kubeConfig.NetworkPluginName
+			cfg.NetworkCIDR = kubeConfig.NetworkCIDR
+			cfg.HostSubnetLength = kubeConfig.HostSubnetLength
+		} else if len(masterConfig.KubernetesAddr) > 0 {
+			if err := cfg.KubernetesAddr.Set(masterConfig.KubernetesAddr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	nodeConfig, err := api.ReadNodeConfig(cfg.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if nodeConfig.Kind != "NodeConfig" {
+		return fmt.Errorf("%q is not a recognized MasterConfig or NodeConfig (kind %q)", cfg.ConfigFile, nodeConfig.Kind)
+	}
+	if err := cfg.BindAddr.Set(nodeConfig.BindHost); err != nil {
+		return err
+	}
+	if err := cfg.MasterAddr.Set(nodeConfig.MasterAddr); err != nil {
+		return err
+	}
+	cfg.Hostname = nodeConfig.NodeHost
+	cfg.VolumeDir = nodeConfig.VolumeDirectory
+	cfg.MasterServiceNamespace = nodeConfig.MasterServiceNamespace
+	cfg.NetworkPluginName = nodeConfig.NetworkPluginName
+	return nil
+}
+
+// writeConfigFile persists the configuration that the provided flags/defaults would
+// otherwise start the server with, so administrators can diff successive runs instead
+// of recomputing the full flag surface every restart.
+func writeConfigFile(cfg *config, startMaster, startNode, startKube bool) error {
+	if startMaster {
+		assetAddr := cfg.AssetAddr
+		if len(assetAddr) == 0 {
+			assetAddr = net.JoinHostPort(cfg.MasterAddr.Host, strconv.Itoa(cfg.BindAddr.Port+1))
+		}
+
+		masterConfig := &api.MasterConfig{
+			TypeMeta: kapi.TypeMeta{Kind: "MasterConfig"},
+
+			BindAddr:   cfg.BindAddr.String(),
+			MasterAddr: cfg.MasterAddr.String(),
+			AssetAddr:  assetAddr,
+
+			EtcdClientInfo: api.EtcdConnectionInfo{URLs: []string{cfg.EtcdAddr.String()}},
+			EtcdStorageConfig: api.EtcdStorageConfig{
+				OpenShiftStorageVersion:  cfg.StorageVersion,
+				OpenShiftStoragePrefix:   cfg.StoragePrefix,
+				KubernetesStorageVersion: cfg.KubeStorageVersion,
+				KubernetesStoragePrefix:  cfg.KubeStoragePrefix,
+			},
+
+			CertDir: cfg.CertDir,
+
+			CORSAllowedOrigins:    []string(cfg.CORSAllowedOrigins),
+			RequireAuthentication: cfg.RequireAuthentication,
+			SessionSecrets:        cfg.SessionSecrets,
+
+			PortalNet: cfg.PortalNet.String(),
+
+			MasterServiceNamespace: cfg.MasterServiceNamespace,
+			SchedulerConfigFile:    cfg.SchedulerConfigFile,
+		}
+		if startKube {
+			masterConfig.KubernetesMasterConfig = &api.KubernetesMasterConfig{
+				MasterHost:     cfg.MasterAddr.Host,
+				MasterPort:     cfg.MasterAddr.Port,
+				NodeList:       []string(cfg.NodeList),
+				ServicesSubnet: cfg.PortalNet.String(),
+
+				NetworkPluginName: cfg.NetworkPluginName,
+				NetworkCIDR:       cfg.NetworkCIDR,
+				HostSubnetLength:  cfg.HostSubnetLength,
+			}
+		} else {
+			masterConfig.KubernetesAddr = cfg.KubernetesAddr.String()
+		}
+		masterConfigFile := filepath.Join(cfg.WriteConfigTo, "master-config.yaml")
+		if err := api.WriteMasterConfig(masterConfigFile, masterConfig); err != nil {
+			return err
+		}
+		glog.Infof("Wrote master configuration to %s", masterConfigFile)
+	}
+
+	if startNode {
+		nodeConfig := &api.NodeConfig{
+			TypeMeta: kapi.TypeMeta{Kind: "NodeConfig"},
+
+			NodeHost:   cfg.Hostname,
+			BindHost:   cfg.BindAddr.Host,
+			MasterAddr: cfg.MasterAddr.String(),
+
+			VolumeDirectory: cfg.VolumeDir,
+
+			NetworkPluginName: cfg.NetworkPluginName,
+
+			MasterServiceNamespace: cfg.MasterServiceNamespace,
+		}
+		nodeConfigFile := filepath.Join(cfg.WriteConfigTo, "node-config.yaml")
+		if err := api.WriteNodeConfig(nodeConfigFile, nodeConfig); err != nil {
+			return err
+		}
+		glog.Infof("Wrote node configuration to %s", nodeConfigFile)
+	}
+
+	return nil
+}
+
+// newServiceAccountTokenGetter bootstraps an RSA signing keypair under cfg.CertDir (if
+// one does not already exist) and returns a ServiceAccountTokenGetter that the API
+// authenticator can use to validate the JWTs minted for service accounts. When this
+// master is running against its own embedded Kubernetes (startKube), tokens are read
+// and validated directly against etcd using the Kubernetes storage version/prefix;
+// otherwise validation falls back to calling the external Kubernetes apiserver, since
+// this master has no direct access to its storage.
+func newServiceAccountTokenGetter(cfg *config, osmaster *origin.MasterConfig, etcdHelper tools.EtcdHelper, startKube bool) (origin.ServiceAccountTokenGetter, error) {
+	signer, err := crypto.InitServiceAccountTokenSigner(cfg.CertDir)
+	if err != nil {
+		return nil, err
+	}
+	osmaster.ServiceAccountTokenSigner = signer
+
+	if startKube {
+		return origin.NewEtcdServiceAccountTokenGetter(etcdHelper, cfg.KubeStorageVersion), nil
+	}
+	return origin.NewExternalServiceAccountTokenGetter(osmaster.KubeClient()), nil
+}
+
 // env returns an environment variable or a default value if not specified.
 func env(key string, defaultValue string) string {
 	val := os.Getenv(key)
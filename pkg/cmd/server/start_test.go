@@ -0,0 +1,72 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	etcdclient "github.com/coreos/go-etcd/etcd"
+)
+
+func TestCopyEtcdNodeLeaf(t *testing.T) {
+	node := &etcdclient.Node{Key: "/openshift.io/builds/foo", Value: "bar"}
+
+	copied := map[string]string{}
+	err := copyEtcdNode(node, "/openshift.io", "/registry", func(key, value string) error {
+		copied[key] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{"/registry/builds/foo": "bar"}
+	if !reflect.DeepEqual(copied, want) {
+		t.Errorf("copyEtcdNode() copied = %v, want %v", copied, want)
+	}
+}
+
+func TestCopyEtcdNodeDir(t *testing.T) {
+	node := &etcdclient.Node{
+		Key: "/openshift.io",
+		Dir: true,
+		Nodes: etcdclient.Nodes{
+			{Key: "/openshift.io/builds", Dir: true, Nodes: etcdclient.Nodes{
+				{Key: "/openshift.io/builds/foo", Value: "1"},
+				{Key: "/openshift.io/builds/bar", Value: "2"},
+			}},
+			{Key: "/openshift.io/deployments/foo", Value: "3"},
+		},
+	}
+
+	var copiedKeys []string
+	err := copyEtcdNode(node, "/openshift.io", "/registry", func(key, value string) error {
+		copiedKeys = append(copiedKeys, key+"="+value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Strings(copiedKeys)
+	want := []string{
+		"/registry/builds/bar=2",
+		"/registry/builds/foo=1",
+		"/registry/deployments/foo=3",
+	}
+	if !reflect.DeepEqual(copiedKeys, want) {
+		t.Errorf("copyEtcdNode() copied = %v, want %v", copiedKeys, want)
+	}
+}
+
+func TestCopyEtcdNodeWrongPrefix(t *testing.T) {
+	node := &etcdclient.Node{Key: "/other/builds/foo", Value: "bar"}
+
+	err := copyEtcdNode(node, "/openshift.io", "/registry", func(key, value string) error {
+		t.Fatalf("set should not be called for a key outside fromPrefix, got %q", key)
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error for a key outside fromPrefix, got nil")
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	kapi "github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
+	"github.com/golang/glog"
+)
+
+// minRunnerBackoff and maxRunnerBackoff bound how aggressively a panicking Runner is
+// restarted.
+const (
+	minRunnerBackoff = 1 * time.Second
+	maxRunnerBackoff = 30 * time.Second
+)
+
+// Runner is a named subsystem the Supervisor manages, e.g. the apiserver, a
+// controller, or the kubelet. Start is handed the Supervisor's stopCh, closed once a
+// termination signal is received, and is expected to block until it returns -- that is
+// what lets a registered Runner stay supervised for its actual lifetime instead of
+// just the moment it takes to launch. A panic during Start is recovered, logged, and
+// causes a restart after backoff; a clean return is treated as "this runner is done"
+// and is not restarted.
+type Runner struct {
+	Name  string
+	Start func(stopCh <-chan struct{})
+}
+
+// Supervisor replaces the bare `select {}` that used to end start(): it keeps each
+// registered subsystem alive by recovering and restarting (with backoff) only those
+// that panic, and performs an orderly shutdown -- closing its stop channel and running
+// registered shutdown closers (such as one that records a final lifecycle event) --
+// when it receives SIGINT or SIGTERM.
+type Supervisor struct {
+	stopCh chan struct{}
+
+	mu      sync.Mutex
+	runners []Runner
+	closers []func() error
+}
+
+// NewSupervisor returns an empty Supervisor. Register subsystems with Register, then
+// call Wait to block until a termination signal is received.
+func NewSupervisor() *Supervisor {
+	return &Supervisor{stopCh: make(chan struct{})}
+}
+
+// Adapt wraps a fire-and-forget setup function -- one that launches its own
+// background goroutine and returns almost immediately, like every RunXxx() call this
+// supervisor manages -- so it can be passed to RunOnce, which calls it exactly once and
+// does not care whether or when it returns.
+func Adapt(start func()) func(stopCh <-chan struct{}) {
+	return func(stopCh <-chan struct{}) { start() }
+}
+
+// AdaptSupervised wraps a fire-and-forget setup function for Register. Register's
+// supervision only lasts as long as Start itself is running, so AdaptSupervised calls
+// start and then blocks on stopCh -- keeping the Runner (and therefore the subsystem it
+// launched) under supervision for as long as the server is up, instead of Register's
+// goroutine returning, and superviseForever giving up on it, within milliseconds of
+// startup. Because start's background goroutine is not the one recover() runs in, a
+// panic deep inside it still cannot be caught here; AdaptSupervised only guards against
+// start panicking synchronously (e.g. a listener failing to bind) or returning early.
+func AdaptSupervised(start func()) func(stopCh <-chan struct{}) {
+	return func(stopCh <-chan struct{}) {
+		start()
+		<-stopCh
+	}
+}
+
+// Register starts a named subsystem under supervision. Start is invoked immediately
+// in its own goroutine; Register does not block.
+func (s *Supervisor) Register(name string, start func(stopCh <-chan struct{})) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := Runner{Name: name, Start: start}
+	s.runners = append(s.runners, r)
+	go s.superviseForever(r)
+}
+
+// RunOnce runs a named subsystem synchronously, in the caller's own goroutine,
+// recovering and logging (and recording a lifecycle event for) a panic instead of
+// letting it take down the whole process -- but, unlike Register, it does not retry
+// and does not wait for anything beyond start itself completing. Use it for startup
+// gates that the rest of start() must block on, such as waiting for an overlay network
+// to come up before the kubelet starts.
+func (s *Supervisor) RunOnce(name string, start func(stopCh <-chan struct{})) {
+	s.runOnce(Runner{Name: name, Start: start})
+}
+
+// OnShutdown registers a cleanup function to run, in order, once a termination
+// signal has been received and before Wait returns (e.g. closing an etcd client or
+// flushing an event recorder).
+func (s *Supervisor) OnShutdown(closer func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closers = append(s.closers, closer)
+}
+
+// superviseForever runs r.Start once. If it panics, the panic is recovered, logged,
+// and recorded as a lifecycle event, and r.Start is retried after an exponential
+// backoff (capped at maxRunnerBackoff). If r.Start returns normally -- which, for the
+// AdaptSupervised-wrapped runners registered today, only happens once stopCh has been
+// closed -- superviseForever simply stops; there is nothing left to restart.
+func (s *Supervisor) superviseForever(r Runner) {
+	backoff := minRunnerBackoff
+	for {
+		panicked := s.runOnce(r)
+		if !panicked {
+			return
+		}
+
+		glog.Warningf("%s panicked, restarting in %s", r.Name, backoff)
+		time.Sleep(backoff)
+		if backoff < maxRunnerBackoff {
+			backoff *= 2
+		}
+		if backoff > maxRunnerBackoff {
+			backoff = maxRunnerBackoff
+		}
+	}
+}
+
+// runOnce invokes r.Start(stopCh) and reports whether it panicked.
+func (s *Supervisor) runOnce(r Runner) (panicked bool) {
+	defer func() {
+		if reason := recover(); reason != nil {
+			panicked = true
+			glog.Errorf("%s panicked: %v", r.Name, reason)
+			record.Eventf(&kapi.ObjectReference{Kind: "ComponentStatus", Name: r.Name}, "Panicked", "%s panicked: %v", r.Name, reason)
+		}
+	}()
+	r.Start(s.stopCh)
+	return false
+}
+
+// Wait blocks until SIGINT or SIGTERM is received, closes the stop channel passed to
+// every Runner, then runs every registered shutdown closer (in registration order)
+// before returning.
+func (s *Supervisor) Wait() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+	glog.Infof("Received %s, shutting down", sig)
+
+	close(s.stopCh)
+
+	s.mu.Lock()
+	closers := s.closers
+	s.mu.Unlock()
+
+	for _, closer := range closers {
+		if err := closer(); err != nil {
+			glog.Errorf("Error during shutdown: %v", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSuperviseForeverRestartsOnlyOnPanic(t *testing.T) {
+	s := NewSupervisor()
+	var calls int32
+	r := Runner{
+		Name: "test",
+		Start: func(stopCh <-chan struct{}) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				panic("boom")
+			}
+		},
+	}
+
+	s.superviseForever(r)
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Start called %d times, want 2 (one panic, then one clean return with no further retry)", got)
+	}
+}
+
+func TestRunOnceRecoversPanicAndDoesNotRetry(t *testing.T) {
+	s := NewSupervisor()
+	var calls int32
+
+	s.RunOnce("panicky", func(stopCh <-chan struct{}) {
+		atomic.AddInt32(&calls, 1)
+		panic("boom")
+	})
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Start called %d times, want 1 (RunOnce must not retry)", got)
+	}
+}
+
+func TestRegisterStaysSupervisedUntilStopChCloses(t *testing.T) {
+	s := NewSupervisor()
+	started := make(chan struct{})
+	var returned int32
+
+	s.Register("test", func(stopCh <-chan struct{}) {
+		close(started)
+		<-stopCh
+		atomic.StoreInt32(&returned, 1)
+	})
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("registered runner never started")
+	}
+
+	if atomic.LoadInt32(&returned) != 0 {
+		t.Fatal("runner returned before its stopCh was closed")
+	}
+
+	close(s.stopCh)
+
+	for i := 0; i < 100 && atomic.LoadInt32(&returned) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&returned) != 1 {
+		t.Error("runner did not return after stopCh was closed")
+	}
+}
+
+func TestWaitClosesStopChAndRunsClosersInOrder(t *testing.T) {
+	s := NewSupervisor()
+	var order []int
+
+	s.OnShutdown(func() error { order = append(order, 1); return nil })
+	s.OnShutdown(func() error { order = append(order, 2); return nil })
+
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+
+	// Give Wait a moment to install its signal handler before we send one.
+	time.Sleep(50 * time.Millisecond)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("unable to signal self: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return after SIGTERM")
+	}
+
+	select {
+	case <-s.stopCh:
+	default:
+		t.Error("Wait did not close stopCh")
+	}
+
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Errorf("shutdown closers ran as %v, want [1 2]", order)
+	}
+}